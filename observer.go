@@ -0,0 +1,109 @@
+package watchdog
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Observer is a pluggable sink for the events seen by the cgroup-driven
+// watchdogs, so that operators can graph what the watchdog is seeing and
+// alert on divergence between cgroup-reported usage and Go's own heap
+// stats — useful for diagnosing whether OOMs are being driven by Go heap
+// growth or by off-heap/cgo allocations the watchdog can't see.
+type Observer interface {
+	// OnTick is called on every successful poll (or PSI wakeup) with the
+	// current usage and limit, as reported by the cgroup.
+	OnTick(usage, limit uint64)
+	// OnPolicyFire is called whenever the watchdog's policy decides to act,
+	// e.g. forcing a GC cycle. reason is a short, policy-specific label.
+	OnPolicyFire(reason string)
+	// OnError is called whenever a tick fails to fetch cgroup stats.
+	OnError(err error)
+}
+
+// noopObserver is the default Observer used when the caller doesn't supply
+// one, so driver code can call through opt.Observer unconditionally.
+type noopObserver struct{}
+
+func (noopObserver) OnTick(usage, limit uint64) {}
+func (noopObserver) OnPolicyFire(reason string) {}
+func (noopObserver) OnError(err error)          {}
+
+// observerOrNoop returns o if non-nil, or a noopObserver otherwise.
+func observerOrNoop(o Observer) Observer {
+	if o == nil {
+		return noopObserver{}
+	}
+	return o
+}
+
+// PrometheusObserver is an Observer that exposes what the watchdog is seeing
+// as Prometheus metrics. It implements prometheus.Collector so it can be
+// registered directly with a prometheus.Registerer.
+type PrometheusObserver struct {
+	limit           prometheus.Gauge
+	usage           prometheus.Gauge
+	utilization     prometheus.Gauge
+	gcForcedTotal   prometheus.Counter
+	statErrorsTotal prometheus.Counter
+}
+
+var _ Observer = (*PrometheusObserver)(nil)
+var _ prometheus.Collector = (*PrometheusObserver)(nil)
+
+// NewPrometheusObserver constructs a PrometheusObserver with the standard
+// memwd metric names and no labels. Register it with a prometheus.Registerer
+// before passing it to CgroupDriven/CgroupDrivenPod via CgroupDrivenOpts.
+func NewPrometheusObserver() *PrometheusObserver {
+	return &PrometheusObserver{
+		limit: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "memwd_cgroup_limit_bytes",
+			Help: "The memory limit of the cgroup driving the watchdog, in bytes.",
+		}),
+		usage: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "memwd_cgroup_usage_bytes",
+			Help: "The memory usage of the cgroup driving the watchdog, in bytes.",
+		}),
+		utilization: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "memwd_utilization_ratio",
+			Help: "The ratio of cgroup memory usage to limit, as last observed by the watchdog.",
+		}),
+		gcForcedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "memwd_gc_forced_total",
+			Help: "The total number of times the watchdog's policy forced a GC cycle.",
+		}),
+		statErrorsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "memwd_stat_errors_total",
+			Help: "The total number of times the watchdog failed to fetch cgroup memory stats.",
+		}),
+	}
+}
+
+func (p *PrometheusObserver) OnTick(usage, limit uint64) {
+	p.usage.Set(float64(usage))
+	p.limit.Set(float64(limit))
+	if limit > 0 {
+		p.utilization.Set(float64(usage) / float64(limit))
+	}
+}
+
+func (p *PrometheusObserver) OnPolicyFire(reason string) {
+	p.gcForcedTotal.Inc()
+}
+
+func (p *PrometheusObserver) OnError(err error) {
+	p.statErrorsTotal.Inc()
+}
+
+func (p *PrometheusObserver) Describe(ch chan<- *prometheus.Desc) {
+	p.limit.Describe(ch)
+	p.usage.Describe(ch)
+	p.utilization.Describe(ch)
+	p.gcForcedTotal.Describe(ch)
+	p.statErrorsTotal.Describe(ch)
+}
+
+func (p *PrometheusObserver) Collect(ch chan<- prometheus.Metric) {
+	p.limit.Collect(ch)
+	p.usage.Collect(ch)
+	p.utilization.Collect(ch)
+	p.gcForcedTotal.Collect(ch)
+	p.statErrorsTotal.Collect(ch)
+}
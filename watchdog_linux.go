@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/containerd/cgroups/v3"
 	"github.com/containerd/cgroups/v3/cgroup1"
 	"github.com/containerd/cgroups/v3/cgroup2"
+	"golang.org/x/sys/unix"
 )
 
 var (
@@ -16,6 +20,295 @@ var (
 	//memSubsystem = cgroups.SingleSubsystem(cgroups.V1, cgroups.Memory)
 )
 
+// PressureThresholds configures the stall/window pairs written to the PSI
+// `memory.pressure` control file to arm `some` and `full` triggers. A zero
+// value for either leaves that trigger disarmed. See
+// https://docs.kernel.org/accounting/psi.html for the semantics of stall vs.
+// window.
+type PressureThresholds struct {
+	// Some is the stall threshold for the "some" PSI trigger, i.e. fired when
+	// at least one task is stalled on memory. Zero disarms this trigger.
+	Some time.Duration
+	// Full is the stall threshold for the "full" PSI trigger, i.e. fired when
+	// all tasks in the cgroup are stalled on memory simultaneously. Zero
+	// disarms this trigger.
+	Full time.Duration
+	// Window is the tracking window within which the stall threshold is
+	// evaluated. Defaults to 1s if zero.
+	Window time.Duration
+}
+
+// psiTriggerLine renders a PSI trigger line of the form "<kind> <stall_us>
+// <window_us>" as expected by memory.pressure.
+func psiTriggerLine(kind string, stall, window time.Duration) string {
+	if window == 0 {
+		window = time.Second
+	}
+	return fmt.Sprintf("%s %d %d", kind, stall.Microseconds(), window.Microseconds())
+}
+
+// CgroupPressureDriven initializes a cgroup v2 watchdog that reacts to Linux
+// PSI (Pressure Stall Information) notifications on `memory.pressure`,
+// instead of polling on a fixed frequency. It arms the thresholds described
+// by PressureThresholds (one memory.pressure fd per armed trigger), then
+// starts a goroutine that blocks on poll(2) for POLLPRI wakeups on those fds,
+// invoking the policy synchronously as soon as the kernel
+// reports memory stall. It also watches `memory.events.local` for bumps to
+// the high/max/oom counters via inotify, so the policy fires immediately
+// when the kernel throttles or OOM-kills within the cgroup, rather than
+// waiting for the next PSI wakeup.
+//
+// PSI requires cgroup v2 and a kernel >= 4.20 with
+// CONFIG_PSI=y. If PSI is unavailable, this function returns an error and
+// the caller should fall back to CgroupDriven, which polls at a fixed
+// frequency instead.
+func CgroupPressureDriven(policyCtor PolicyCtor, thresholds PressureThresholds, opts ...CgroupDrivenOpts) (err error, stopFn func()) {
+	var opt CgroupDrivenOpts
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	observer := observerOrNoop(opt.Observer)
+
+	switch cgroups.Mode() {
+	case cgroups.Unified, cgroups.Hybrid:
+	default:
+		return fmt.Errorf("PSI-driven watchdog requires cgroup v2; cgroup mode is %v", cgroups.Mode()), nil
+	}
+
+	path, loadOpts, err := cgroupv2Discover(opt.Config)
+	if err != nil {
+		return err, nil
+	}
+
+	cgroup, err := cgroup2.Load(path, loadOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to load cgroup2 for process: %w", err), nil
+	}
+
+	mountpoint := opt.Config.Mountpoint
+	if mountpoint == "" {
+		mountpoint = "/sys/fs/cgroup"
+	}
+
+	var limit uint64
+	if stat, err := cgroup.Stat(); err != nil {
+		return fmt.Errorf("failed to load cgroup2 memory stats: %w", err), nil
+	} else if stat.Memory == nil {
+		return fmt.Errorf("cgroup2 memory stats are nil; aborting"), nil
+	} else {
+		limit = stat.Memory.UsageLimit
+	}
+
+	if limit == 0 {
+		return fmt.Errorf("cgroup2 limit is 0; refusing to start memory watchdog"), nil
+	}
+
+	// The kernel requires a separate open(2) of memory.pressure per armed
+	// trigger: a second write(2) on an fd that already has a trigger
+	// registered fails with EBUSY. So we open one fd per threshold below.
+	type psiTrigger struct {
+		kind  string
+		stall time.Duration
+	}
+	var triggers []psiTrigger
+	if thresholds.Some > 0 {
+		triggers = append(triggers, psiTrigger{"some", thresholds.Some})
+	}
+	if thresholds.Full > 0 {
+		triggers = append(triggers, psiTrigger{"full", thresholds.Full})
+	}
+	if len(triggers) == 0 {
+		return fmt.Errorf("no PSI thresholds armed; set Some and/or Full on PressureThresholds"), nil
+	}
+
+	pressureFile := fmt.Sprintf("%s%s/memory.pressure", mountpoint, path)
+	var psiFiles []*os.File
+	closePSIFiles := func() {
+		for _, f := range psiFiles {
+			f.Close()
+		}
+	}
+	for _, trg := range triggers {
+		f, err := os.OpenFile(pressureFile, os.O_RDWR, 0)
+		if err != nil {
+			closePSIFiles()
+			return fmt.Errorf("PSI not available at %s (kernel < 4.20 or cgroup v1?): %w", pressureFile, err), nil
+		}
+		if _, err := f.WriteString(psiTriggerLine(trg.kind, trg.stall, thresholds.Window)); err != nil {
+			f.Close()
+			closePSIFiles()
+			return fmt.Errorf("failed to arm PSI '%s' trigger: %w", trg.kind, err), nil
+		}
+		psiFiles = append(psiFiles, f)
+	}
+
+	policy, err := policyCtor(limit)
+	if err != nil {
+		closePSIFiles()
+		return fmt.Errorf("failed to construct policy with limit %d: %w", limit, err), nil
+	}
+
+	if err := start(UtilizationProcess); err != nil {
+		closePSIFiles()
+		return err, nil
+	}
+
+	eventsFd, err := unix.InotifyInit1(unix.IN_NONBLOCK)
+	if err != nil {
+		log.Printf("failed to initialize inotify watcher on memory.events.local, continuing with PSI only: %s", err)
+		eventsFd = -1
+	} else if _, err := unix.InotifyAddWatch(eventsFd, fmt.Sprintf("%s%s/memory.events.local", mountpoint, path), unix.IN_MODIFY); err != nil {
+		log.Printf("failed to watch memory.events.local, continuing with PSI only: %s", err)
+		unix.Close(eventsFd)
+		eventsFd = -1
+	}
+
+	usageFn := func() (uint64, error) {
+		stat, err := cgroup.Stat()
+		if err != nil {
+			return 0, err
+		} else if stat.Memory == nil {
+			return 0, fmt.Errorf("cgroup2 memory stats are nil; aborting")
+		}
+		return stat.Memory.Usage, nil
+	}
+
+	_watchdog.wg.Add(1)
+	go pressureWatchdog(policy, psiFiles, eventsFd, limit, usageFn, observer)
+
+	return nil, stop
+}
+
+// pressureWatchdog blocks on poll(2) for POLLPRI wakeups on the armed PSI
+// fds (one per armed trigger; and, if available, POLLIN on the
+// memory.events.local inotify fd), and invokes the policy synchronously on
+// every wakeup. Each wakeup is reported to observer as a tick; it is reported
+// as a policy fire, labelled with the wakeup sources, only when the policy's
+// Evaluate returns true.
+func pressureWatchdog(policy Policy, psiFiles []*os.File, eventsFd int, limit uint64, usageFn func() (uint64, error), observer Observer) {
+	defer _watchdog.wg.Done()
+	for _, f := range psiFiles {
+		defer f.Close()
+	}
+	if eventsFd >= 0 {
+		defer unix.Close(eventsFd)
+	}
+
+	fds := make([]unix.PollFd, 0, len(psiFiles)+1)
+	for _, f := range psiFiles {
+		fds = append(fds, unix.PollFd{Fd: int32(f.Fd()), Events: unix.POLLPRI})
+	}
+	eventsIdx := -1
+	if eventsFd >= 0 {
+		eventsIdx = len(fds)
+		fds = append(fds, unix.PollFd{Fd: int32(eventsFd), Events: unix.POLLIN})
+	}
+
+	for {
+		select {
+		case <-_watchdog.closing:
+			return
+		default:
+		}
+
+		n, err := unix.Poll(fds, 1000)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			log.Printf("memwd: poll on PSI fd failed: %s; aborting pressure watchdog", err)
+			return
+		}
+		if n == 0 {
+			continue
+		}
+
+		var reasons []string
+		for i := range psiFiles {
+			if fds[i].Revents&unix.POLLPRI != 0 {
+				reasons = append(reasons, "psi")
+			}
+		}
+		if eventsIdx >= 0 && fds[eventsIdx].Revents&unix.POLLIN != 0 {
+			// drain the inotify event queue; we don't need to inspect it,
+			// its mere arrival means high/max/oom counters were bumped.
+			buf := make([]byte, 4096)
+			_, _ = unix.Read(eventsFd, buf)
+			reasons = append(reasons, "memory.events.local")
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		usage, err := usageFn()
+		if err != nil {
+			observer.OnError(err)
+			log.Printf("memwd: failed to fetch cgroup memory usage: %s", err)
+			continue
+		}
+		observer.OnTick(usage, limit)
+		if policy.Evaluate(usage) {
+			observer.OnPolicyFire(strings.Join(reasons, ","))
+		}
+	}
+}
+
+// CgroupDrivenOpts tunes the behaviour of CgroupDriven beyond the reactive
+// GC policy it drives.
+type CgroupDrivenOpts struct {
+	// SetGOMEMLimit, when true, additionally calls debug.SetMemoryLimit with
+	// the discovered cgroup limit (scaled by Headroom) so that the Go 1.19+
+	// soft memory limit tracks the cgroup's memory limit. It is re-applied
+	// whenever the cgroup's limit changes between polls. It is skipped
+	// entirely if the user has already set the GOMEMLIMIT environment
+	// variable, so this never overrides an explicit user choice.
+	SetGOMEMLimit bool
+	// Headroom scales the discovered limit before handing it to
+	// debug.SetMemoryLimit, e.g. 0.9 to leave 10% headroom below the cgroup
+	// limit. Defaults to 1.0 (no headroom) if zero.
+	Headroom float64
+	// Config overrides cgroup discovery for hybrid hierarchies and nested
+	// containers. Zero value preserves the default discovery behaviour.
+	Config CgroupDrivenConfig
+	// Observer, if set, is notified of every tick, policy firing and error
+	// seen by the watchdog. See the Observer interface and PrometheusObserver.
+	Observer Observer
+}
+
+// CgroupDrivenConfig overrides cgroup v2 discovery for hybrid hierarchies
+// (v1+v2 mounted at non-standard paths) and nested containers, where the
+// process's own cgroup path does not carry the real memory limit.
+type CgroupDrivenConfig struct {
+	// Mountpoint overrides the default cgroup v2 mountpoint (/sys/fs/cgroup),
+	// e.g. "/sys/fs/cgroup/unified" for hybrid v1+v2 setups.
+	Mountpoint string
+	// CgroupPath, if set, is used verbatim instead of deriving the cgroup
+	// path from /proc/self/cgroup. Useful when the process's own cgroup path
+	// is relative to a parent namespace, e.g. inside nested containers or
+	// Podman pods.
+	CgroupPath string
+}
+
+// observingPolicy wraps a Policy so that every Evaluate call is reported to
+// an Observer when the policy actually decides to act. This lets
+// pollingWatchdog's regular per-tick Evaluate calls feed
+// memwd_gc_forced_total/OnPolicyFire without pollingWatchdog itself needing
+// to know about Observer: Evaluate's bool return already tells us whether
+// the policy fired, so the wrapper just forwards that subset of calls.
+type observingPolicy struct {
+	Policy
+	observer Observer
+	reason   string
+}
+
+func (o observingPolicy) Evaluate(usage uint64) bool {
+	fired := o.Policy.Evaluate(usage)
+	if fired {
+		o.observer.OnPolicyFire(o.reason)
+	}
+	return fired
+}
+
 // CgroupDriven initializes a cgroups-driven watchdog. It will try to discover
 // the memory limit from the cgroup of the process (derived from /proc/self/cgroup),
 // or from the root cgroup path if the PID == 1 (which indicates that the process
@@ -26,12 +319,16 @@ var (
 // This function will return an error immediately if the OS does not support cgroups,
 // or if another error occurs during initialization. The caller can then safely fall
 // back to the system driven watchdog.
-func CgroupDriven(frequency time.Duration, policyCtor PolicyCtor) (err error, stopFn func()) {
+func CgroupDriven(frequency time.Duration, policyCtor PolicyCtor, opts ...CgroupDrivenOpts) (err error, stopFn func()) {
+	var opt CgroupDrivenOpts
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
 	switch cgroups.Mode() {
-	case cgroups.Unified:
-		return cgroupv2Driven(frequency, policyCtor)
+	case cgroups.Unified, cgroups.Hybrid:
+		return cgroupv2Driven(frequency, policyCtor, opt)
 	case cgroups.Legacy:
-		return cgroupv1Driven(frequency, policyCtor)
+		return cgroupv1Driven(frequency, policyCtor, opt)
 	case cgroups.Unavailable:
 		fallthrough
 	default:
@@ -39,7 +336,25 @@ func CgroupDriven(frequency time.Duration, policyCtor PolicyCtor) (err error, st
 	}
 }
 
-func cgroupv1Driven(frequency time.Duration, policyCtor PolicyCtor) (err error, stopFn func()) {
+// maybeSetGOMEMLimit applies debug.SetMemoryLimit for the given cgroup limit
+// if opt.SetGOMEMLimit is set and the user hasn't already configured
+// GOMEMLIMIT themselves. It returns the unscaled cgroup limit it applied
+// (or 0 if it didn't apply anything), for comparison against the cgroup's
+// limit on subsequent calls.
+func maybeSetGOMEMLimit(opt CgroupDrivenOpts, limit uint64) uint64 {
+	if !opt.SetGOMEMLimit || os.Getenv("GOMEMLIMIT") != "" {
+		return 0
+	}
+	headroom := opt.Headroom
+	if headroom == 0 {
+		headroom = 1.0
+	}
+	scaled := int64(float64(limit) * headroom)
+	debug.SetMemoryLimit(scaled)
+	return limit
+}
+
+func cgroupv1Driven(frequency time.Duration, policyCtor PolicyCtor, opt CgroupDrivenOpts) (err error, stopFn func()) {
 	// use self path unless our PID is 1, in which case we're running inside
 	// a container and our limits are in the root path.
 	path := cgroup1.NestedPath("")
@@ -88,30 +403,66 @@ func cgroupv1Driven(frequency time.Duration, policyCtor PolicyCtor) (err error,
 		return err, nil
 	}
 
+	appliedLimit := maybeSetGOMEMLimit(opt, limit)
+	observer := observerOrNoop(opt.Observer)
+	policy = observingPolicy{Policy: policy, observer: observer, reason: "cgroupv1"}
+
 	_watchdog.wg.Add(1)
 	go pollingWatchdog(policy, frequency, limit, func() (uint64, error) {
 		stat, err := cgroup.Stat()
 		if err != nil {
+			observer.OnError(err)
 			return 0, err
 		} else if stat.Memory == nil || stat.Memory.Usage == nil {
-			return 0, fmt.Errorf("cgroup1 memory stats are nil; aborting")
+			err := fmt.Errorf("cgroup1 memory stats are nil; aborting")
+			observer.OnError(err)
+			return 0, err
 		}
+		if newLimit := stat.Memory.Usage.Limit; opt.SetGOMEMLimit && newLimit != 0 && newLimit != appliedLimit {
+			appliedLimit = maybeSetGOMEMLimit(opt, newLimit)
+		}
+		observer.OnTick(stat.Memory.Usage.Usage, stat.Memory.Usage.Limit)
 		return stat.Memory.Usage.Usage, nil
 	})
 
 	return nil, stop
 }
-func cgroupv2Driven(frequency time.Duration, policyCtor PolicyCtor) (err error, stopFn func()) {
-	// use self path unless our PID is 1, in which case we're running inside
-	// a container and our limits are in the root path.
+// cgroupv2Discover resolves the cgroup v2 path and load options to use,
+// honouring an explicit CgroupPath/Mountpoint override in cfg. Absent an
+// override, it derives the path from /proc/self/cgroup, except when running
+// as PID 1 (i.e. as a container's init process), in which case our own
+// cgroup path is relative to a parent namespace and we prefer the nested
+// group path, falling back to the root cgroup.
+func cgroupv2Discover(cfg CgroupDrivenConfig) (path string, opts []cgroup2.InitOpts, err error) {
+	if cfg.Mountpoint != "" {
+		opts = append(opts, cgroup2.WithMountpoint(cfg.Mountpoint))
+	}
+
+	if cfg.CgroupPath != "" {
+		return cfg.CgroupPath, opts, nil
+	}
 
-	pid := os.Getpid()
-	path, err := cgroup2.PidGroupPath(pid)
+	if pid == 1 {
+		if path, err := cgroup2.NestedGroupPath(""); err == nil {
+			return path, opts, nil
+		}
+		return "/", opts, nil
+	}
+
+	path, err = cgroup2.PidGroupPath(pid)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load cgroup2 path for process pid %d: %w", pid, err)
+	}
+	return path, opts, nil
+}
+
+func cgroupv2Driven(frequency time.Duration, policyCtor PolicyCtor, opt CgroupDrivenOpts) (err error, stopFn func()) {
+	path, loadOpts, err := cgroupv2Discover(opt.Config)
 	if err != nil {
-		return fmt.Errorf("failed to load cgroup2 path for process pid %d: %w", pid, err), nil
+		return err, nil
 	}
 
-	cgroup, err := cgroup2.Load(path)
+	cgroup, err := cgroup2.Load(path, loadOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to load cgroup2 for process: %w", err), nil
 	}
@@ -138,16 +489,331 @@ func cgroupv2Driven(frequency time.Duration, policyCtor PolicyCtor) (err error,
 		return err, nil
 	}
 
+	appliedLimit := maybeSetGOMEMLimit(opt, limit)
+	observer := observerOrNoop(opt.Observer)
+	policy = observingPolicy{Policy: policy, observer: observer, reason: "cgroupv2"}
+
 	_watchdog.wg.Add(1)
 	go pollingWatchdog(policy, frequency, limit, func() (uint64, error) {
 		stat, err := cgroup.Stat()
 		if err != nil {
+			observer.OnError(err)
 			return 0, err
 		} else if stat.Memory == nil {
-			return 0, fmt.Errorf("cgroup2 memory stats are nil; aborting")
+			err := fmt.Errorf("cgroup2 memory stats are nil; aborting")
+			observer.OnError(err)
+			return 0, err
+		}
+		if newLimit := stat.Memory.UsageLimit; opt.SetGOMEMLimit && newLimit != 0 && newLimit != appliedLimit {
+			appliedLimit = maybeSetGOMEMLimit(opt, newLimit)
 		}
+		observer.OnTick(stat.Memory.Usage, stat.Memory.UsageLimit)
 		return stat.Memory.Usage, nil
 	})
 
 	return nil, stop
 }
+
+// UsageScope controls how CgroupDrivenPod reads memory usage relative to the
+// hierarchy of cgroups enclosing the process, mirroring the fact that
+// limits (and sometimes usage accounting) in Podman/Kubernetes pods are
+// frequently enforced at a parent "pod slice" cgroup rather than at the
+// leaf cgroup of an individual process.
+type UsageScope int
+
+const (
+	// Leaf reads usage from the process's own (leaf) cgroup only.
+	Leaf UsageScope = iota
+	// Enclosing reads usage from the ancestor cgroup that carries the
+	// tightest limit, e.g. the pod-level slice.
+	Enclosing
+	// HierarchicalMin reads usage as the minimum observed across the leaf
+	// and all of its ancestors, mirroring how the kernel enforces nested
+	// limits hierarchically.
+	HierarchicalMin
+)
+
+// CgroupDrivenPod initializes a cgroups-driven watchdog like CgroupDriven,
+// but additionally walks upward from the process's own cgroup toward the
+// root, collecting the memory limit at each level, and uses the tightest
+// non-zero limit as the effective bound. Usage is read according to scope:
+// see UsageScope. This is necessary for sidecar processes inside a shared
+// Podman pod or Kubernetes pod sandbox, where the real limit is set on an
+// ancestor cgroup rather than the process's own leaf cgroup.
+func CgroupDrivenPod(frequency time.Duration, policyCtor PolicyCtor, scope UsageScope, opts ...CgroupDrivenOpts) (err error, stopFn func()) {
+	var opt CgroupDrivenOpts
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	switch cgroups.Mode() {
+	case cgroups.Unified, cgroups.Hybrid:
+		return cgroupv2DrivenPod(frequency, policyCtor, opt, scope)
+	case cgroups.Legacy:
+		return cgroupv1DrivenPod(frequency, policyCtor, opt, scope)
+	case cgroups.Unavailable:
+		fallthrough
+	default:
+		return fmt.Errorf("Cgroups not supported in this environment"), func() {}
+	}
+}
+
+// tightestLimit returns the smallest non-zero value in limits and its index,
+// mirroring how the kernel enforces cgroup limits hierarchically: the
+// tightest ancestor wins. Returns (0, -1) if every limit is zero.
+func tightestLimit(limits []uint64) (limit uint64, index int) {
+	index = -1
+	for i, l := range limits {
+		if l != 0 && (limit == 0 || l < limit) {
+			limit, index = l, i
+		}
+	}
+	return limit, index
+}
+
+// cgroupv2PodLevel pairs a loaded cgroup2 manager with the memory limit and
+// usage observed on it at discovery time.
+type cgroupv2PodLevel struct {
+	cgroup *cgroup2.Manager
+	limit  uint64
+	usage  uint64
+}
+
+// cgroupv2PodLevels loads the given path and each of its ancestors up to and
+// including the root, ordered leaf-first, recording the memory limit and
+// usage observed at each level.
+func cgroupv2PodLevels(path string, loadOpts []cgroup2.InitOpts) ([]cgroupv2PodLevel, error) {
+	var levels []cgroupv2PodLevel
+	for p := path; ; p = filepath.Dir(p) {
+		cg, err := cgroup2.Load(p, loadOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cgroup2 at %q: %w", p, err)
+		}
+		stat, err := cg.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cgroup2 memory stats at %q: %w", p, err)
+		}
+		var limit, usage uint64
+		if stat.Memory != nil {
+			limit, usage = stat.Memory.UsageLimit, stat.Memory.Usage
+		}
+		levels = append(levels, cgroupv2PodLevel{cgroup: cg, limit: limit, usage: usage})
+		if p == "/" || p == "." {
+			break
+		}
+	}
+	return levels, nil
+}
+
+func cgroupv2DrivenPod(frequency time.Duration, policyCtor PolicyCtor, opt CgroupDrivenOpts, scope UsageScope) (err error, stopFn func()) {
+	path, loadOpts, err := cgroupv2Discover(opt.Config)
+	if err != nil {
+		return err, nil
+	}
+
+	levels, err := cgroupv2PodLevels(path, loadOpts)
+	if err != nil {
+		return err, nil
+	}
+
+	podLimits := make([]uint64, len(levels))
+	for i, lvl := range levels {
+		podLimits[i] = lvl.limit
+	}
+	limit, enclosing := tightestLimit(podLimits)
+	if limit == 0 {
+		return fmt.Errorf("no cgroup in the hierarchy of %q has a non-zero memory limit; refusing to start memory watchdog", path), nil
+	}
+
+	policy, err := policyCtor(limit)
+	if err != nil {
+		return fmt.Errorf("failed to construct policy with limit %d: %w", limit, err), nil
+	}
+
+	if err := start(UtilizationProcess); err != nil {
+		return err, nil
+	}
+
+	appliedLimit := maybeSetGOMEMLimit(opt, limit)
+	observer := observerOrNoop(opt.Observer)
+	policy = observingPolicy{Policy: policy, observer: observer, reason: "cgroupv2-pod"}
+
+	usageFn := func() (uint64, error) {
+		levels, err := cgroupv2PodLevels(path, loadOpts)
+		if err != nil {
+			return 0, err
+		}
+		switch scope {
+		case Enclosing:
+			if enclosing >= len(levels) {
+				return 0, fmt.Errorf("enclosing cgroup level %d no longer present in hierarchy of %q", enclosing, path)
+			}
+			return levels[enclosing].usage, nil
+		case HierarchicalMin:
+			min := levels[0].usage
+			for _, lvl := range levels[1:] {
+				if lvl.usage < min {
+					min = lvl.usage
+				}
+			}
+			return min, nil
+		case Leaf:
+			fallthrough
+		default:
+			return levels[0].usage, nil
+		}
+	}
+
+	_watchdog.wg.Add(1)
+	go pollingWatchdog(policy, frequency, limit, func() (uint64, error) {
+		usage, err := usageFn()
+		if err != nil {
+			observer.OnError(err)
+			return 0, err
+		}
+		if opt.SetGOMEMLimit {
+			if levels, lerr := cgroupv2PodLevels(path, loadOpts); lerr == nil && enclosing < len(levels) {
+				if newLimit := levels[enclosing].limit; newLimit != 0 && newLimit != appliedLimit {
+					appliedLimit = maybeSetGOMEMLimit(opt, newLimit)
+				}
+			}
+		}
+		observer.OnTick(usage, limit)
+		return usage, nil
+	})
+
+	return nil, stop
+}
+
+func cgroupv1DrivenPod(frequency time.Duration, policyCtor PolicyCtor, opt CgroupDrivenOpts, scope UsageScope) (err error, stopFn func()) {
+	path := cgroup1.NestedPath("")
+	if pid == 1 {
+		path = cgroup1.RootPath
+	}
+
+	memoryHierarchy := func() ([]cgroup1.Subsystem, error) {
+		system, err := cgroup1.Default()
+		if err != nil {
+			return nil, err
+		}
+		out := []cgroup1.Subsystem{}
+		for _, v := range system {
+			switch v.Name() {
+			case cgroup1.Memory:
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	}
+
+	load := func(p cgroup1.Path) (cgroup1.Cgroup, uint64, uint64, error) {
+		cg, err := cgroup1.Load(p, cgroup1.WithHiearchy(memoryHierarchy))
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to load cgroup1 at %v: %w", p, err)
+		}
+		stat, err := cg.Stat()
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to load cgroup1 memory stats: %w", err)
+		}
+		if stat.Memory == nil || stat.Memory.Usage == nil {
+			return cg, 0, 0, nil
+		}
+		return cg, stat.Memory.Usage.Limit, stat.Memory.Usage.Usage, nil
+	}
+
+	// cgroup1.Path is a function (not a string), so to walk the hierarchy we
+	// resolve it once to a concrete subsystem-relative path, then walk
+	// string ancestors with cgroup1.StaticPath, mirroring how
+	// cgroupv2PodLevels walks with filepath.Dir.
+	subPath, err := path(cgroup1.Memory)
+	if err != nil {
+		return fmt.Errorf("failed to resolve cgroup1 memory path: %w", err), nil
+	}
+
+	// walk the hierarchy from the process's own path up to the root,
+	// collecting the limit and usage seen at each level.
+	type level struct {
+		cgroup cgroup1.Cgroup
+		limit  uint64
+		usage  uint64
+	}
+	var levels []level
+	for p := subPath; ; p = filepath.Dir(p) {
+		cg, limit, usage, err := load(cgroup1.StaticPath(p))
+		if err != nil {
+			return err, nil
+		}
+		levels = append(levels, level{cgroup: cg, limit: limit, usage: usage})
+		if p == "/" || p == "." {
+			break
+		}
+	}
+
+	podLimits := make([]uint64, len(levels))
+	for i, lvl := range levels {
+		podLimits[i] = lvl.limit
+	}
+	limit, enclosing := tightestLimit(podLimits)
+	if limit == 0 {
+		return fmt.Errorf("no cgroup1 in the hierarchy has a non-zero memory limit; refusing to start memory watchdog"), nil
+	}
+
+	policy, err := policyCtor(limit)
+	if err != nil {
+		return fmt.Errorf("failed to construct policy with limit %d: %w", limit, err), nil
+	}
+
+	if err := start(UtilizationProcess); err != nil {
+		return err, nil
+	}
+
+	appliedLimit := maybeSetGOMEMLimit(opt, limit)
+	observer := observerOrNoop(opt.Observer)
+	policy = observingPolicy{Policy: policy, observer: observer, reason: "cgroupv1-pod"}
+
+	target := levels[0].cgroup
+	if scope == Enclosing {
+		target = levels[enclosing].cgroup
+	}
+
+	_watchdog.wg.Add(1)
+	go pollingWatchdog(policy, frequency, limit, func() (uint64, error) {
+		stat, err := target.Stat()
+		if err != nil {
+			observer.OnError(err)
+			return 0, err
+		} else if stat.Memory == nil || stat.Memory.Usage == nil {
+			err := fmt.Errorf("cgroup1 memory stats are nil; aborting")
+			observer.OnError(err)
+			return 0, err
+		}
+		if opt.SetGOMEMLimit {
+			// Always track the enclosing (tightest) ancestor's limit here,
+			// regardless of scope: for Leaf/HierarchicalMin, target's own
+			// limit is frequently 0/"max" while the real, governing limit
+			// sits on a pod-level ancestor, so re-applying based on target's
+			// own limit would silently never fire. Mirrors cgroupv2DrivenPod.
+			if enclosingStat, serr := levels[enclosing].cgroup.Stat(); serr == nil && enclosingStat.Memory != nil && enclosingStat.Memory.Usage != nil {
+				if newLimit := enclosingStat.Memory.Usage.Limit; newLimit != 0 && newLimit != appliedLimit {
+					appliedLimit = maybeSetGOMEMLimit(opt, newLimit)
+				}
+			}
+		}
+		if scope != HierarchicalMin {
+			observer.OnTick(stat.Memory.Usage.Usage, limit)
+			return stat.Memory.Usage.Usage, nil
+		}
+		min := stat.Memory.Usage.Usage
+		for _, lvl := range levels {
+			if lvl.cgroup == target {
+				continue
+			}
+			if s, err := lvl.cgroup.Stat(); err == nil && s.Memory != nil && s.Memory.Usage != nil && s.Memory.Usage.Usage < min {
+				min = s.Memory.Usage.Usage
+			}
+		}
+		observer.OnTick(min, limit)
+		return min, nil
+	})
+
+	return nil, stop
+}
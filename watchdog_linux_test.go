@@ -0,0 +1,94 @@
+package watchdog
+
+import (
+	"os"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestPSITriggerLine(t *testing.T) {
+	tests := map[string]struct {
+		kind   string
+		stall  time.Duration
+		window time.Duration
+		want   string
+	}{
+		"some with explicit window": {
+			kind: "some", stall: 150 * time.Millisecond, window: time.Second,
+			want: "some 150000 1000000",
+		},
+		"full with explicit window": {
+			kind: "full", stall: 50 * time.Millisecond, window: 500 * time.Millisecond,
+			want: "full 50000 500000",
+		},
+		"zero window defaults to one second": {
+			kind: "some", stall: 150 * time.Millisecond, window: 0,
+			want: "some 150000 1000000",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := psiTriggerLine(tc.kind, tc.stall, tc.window); got != tc.want {
+				t.Fatalf("psiTriggerLine(%q, %s, %s) = %q; want %q", tc.kind, tc.stall, tc.window, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMaybeSetGOMEMLimit(t *testing.T) {
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1)) // restore the limit in effect before the test.
+
+	t.Run("disabled", func(t *testing.T) {
+		if applied := maybeSetGOMEMLimit(CgroupDrivenOpts{SetGOMEMLimit: false}, 1000); applied != 0 {
+			t.Fatalf("expected no limit to be applied, got %d", applied)
+		}
+	})
+
+	t.Run("honours GOMEMLIMIT env override", func(t *testing.T) {
+		os.Setenv("GOMEMLIMIT", "100MiB")
+		defer os.Unsetenv("GOMEMLIMIT")
+		if applied := maybeSetGOMEMLimit(CgroupDrivenOpts{SetGOMEMLimit: true}, 1000); applied != 0 {
+			t.Fatalf("expected no limit to be applied when GOMEMLIMIT is set, got %d", applied)
+		}
+	})
+
+	t.Run("applies headroom", func(t *testing.T) {
+		applied := maybeSetGOMEMLimit(CgroupDrivenOpts{SetGOMEMLimit: true, Headroom: 0.9}, 1000)
+		if applied != 1000 {
+			t.Fatalf("expected applied limit to echo back the raw limit, got %d", applied)
+		}
+		if got := debug.SetMemoryLimit(-1); got != 900 {
+			t.Fatalf("expected debug.SetMemoryLimit to have been called with 900 (1000*0.9), got %d", got)
+		}
+	})
+
+	t.Run("defaults headroom to 1.0", func(t *testing.T) {
+		maybeSetGOMEMLimit(CgroupDrivenOpts{SetGOMEMLimit: true}, 1000)
+		if got := debug.SetMemoryLimit(-1); got != 1000 {
+			t.Fatalf("expected debug.SetMemoryLimit to have been called with 1000, got %d", got)
+		}
+	})
+}
+
+func TestTightestLimit(t *testing.T) {
+	tests := map[string]struct {
+		limits    []uint64
+		wantLimit uint64
+		wantIndex int
+	}{
+		"all zero":             {limits: []uint64{0, 0, 0}, wantLimit: 0, wantIndex: -1},
+		"single non-zero":      {limits: []uint64{0, 500, 0}, wantLimit: 500, wantIndex: 1},
+		"leaf tighter than pod": {limits: []uint64{200, 1000}, wantLimit: 200, wantIndex: 0},
+		"pod tighter than leaf": {limits: []uint64{1000, 200}, wantLimit: 200, wantIndex: 1},
+		"empty":                 {limits: nil, wantLimit: 0, wantIndex: -1},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			limit, index := tightestLimit(tc.limits)
+			if limit != tc.wantLimit || index != tc.wantIndex {
+				t.Fatalf("tightestLimit(%v) = (%d, %d); want (%d, %d)", tc.limits, limit, index, tc.wantLimit, tc.wantIndex)
+			}
+		})
+	}
+}